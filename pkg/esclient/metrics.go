@@ -0,0 +1,119 @@
+package esclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultRegistry is a ready-to-use Registerer for callers that don't
+// maintain their own, e.g. esclient.New(name, ns, esclient.WithMetrics(esclient.DefaultRegistry)).
+// MetricsHandler serves exactly this registry.
+var DefaultRegistry = prometheus.NewRegistry()
+
+// MetricsHandler exposes DefaultRegistry in the standard Prometheus text
+// exposition format. Mount it at /metrics alongside
+// k8shandler.MetricsHandler, which covers the legacy curlESService path.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(DefaultRegistry, promhttp.HandlerOpts{})
+}
+
+// metrics holds the Prometheus collectors registered for a Client via
+// WithMetrics. All collectors are labelled with cluster/namespace so that a
+// single registry can track every managed cluster.
+type metrics struct {
+	requestDuration *prometheus.HistogramVec
+	responseSize    *prometheus.HistogramVec
+	retries         *prometheus.CounterVec
+}
+
+var metricsLabels = []string{"cluster", "namespace", "method", "uri_template", "status"}
+
+// requestDuration, responseSize and retries are package vars, registered
+// against a Registerer exactly once via registerMetricsOnce -- mirroring
+// k8shandler.esRequestDuration/RegisterMetrics. Every Client that calls
+// WithMetrics shares these same collectors (differentiated by the
+// cluster/namespace labels) rather than each building and registering its
+// own, since a *Client is constructed once per managed cluster and
+// registering a second HistogramVec/CounterVec with an identical
+// Namespace/Subsystem/Name against the same registry panics.
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "elasticsearch_operator",
+		Subsystem: "esclient",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of Elasticsearch API calls made by the operator.",
+		Buckets:   prometheus.DefBuckets,
+	}, metricsLabels)
+	responseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "elasticsearch_operator",
+		Subsystem: "esclient",
+		Name:      "response_size_bytes",
+		Help:      "Size of Elasticsearch API responses received by the operator.",
+		Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+	}, metricsLabels)
+	retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "elasticsearch_operator",
+		Subsystem: "esclient",
+		Name:      "request_retries_total",
+		Help:      "Number of retries performed for Elasticsearch API calls.",
+	}, metricsLabels)
+)
+
+var registerMetricsOnce sync.Once
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	registerMetricsOnce.Do(func() {
+		reg.MustRegister(requestDuration, responseSize, retries)
+	})
+
+	return &metrics{
+		requestDuration: requestDuration,
+		responseSize:    responseSize,
+		retries:         retries,
+	}
+}
+
+// WithMetrics enables per-call instrumentation on the Client, registering
+// the esclient histograms/counters against reg the first time any Client
+// is built with it. uri_template is the bounded template string each
+// method passes to request() alongside the concrete URI (e.g.
+// "{index}/_settings"), never the literal URI itself, so cardinality
+// stays bounded per distinct API shape regardless of how many
+// indices/templates the operator touches.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(c *Client) {
+		c.metrics = newMetrics(reg)
+	}
+}
+
+func (c *Client) observe(method, uriTemplate string, status int, duration time.Duration, responseSize int, retries int) {
+	if c.metrics == nil {
+		return
+	}
+
+	labels := prometheus.Labels{
+		"cluster":      c.clusterName,
+		"namespace":    c.namespace,
+		"method":       method,
+		"uri_template": uriTemplate,
+		"status":       statusLabel(status),
+	}
+
+	c.metrics.requestDuration.With(labels).Observe(duration.Seconds())
+	c.metrics.responseSize.With(labels).Observe(float64(responseSize))
+	if retries > 0 {
+		c.metrics.retries.With(labels).Add(float64(retries))
+	}
+}
+
+func statusLabel(status int) string {
+	if status == 0 {
+		return "error"
+	}
+
+	return http.StatusText(status)
+}
@@ -0,0 +1,38 @@
+package esclient
+
+// ClusterHealth is the typed response body of a GET _cluster/health call.
+type ClusterHealth struct {
+	Status               string `json:"status"`
+	NumberOfNodes        int32  `json:"number_of_nodes"`
+	NumberOfDataNodes    int32  `json:"number_of_data_nodes"`
+	ActivePrimaryShards  int32  `json:"active_primary_shards"`
+	ActiveShards         int32  `json:"active_shards"`
+	RelocatingShards     int32  `json:"relocating_shards"`
+	InitializingShards   int32  `json:"initializing_shards"`
+	UnassignedShards     int32  `json:"unassigned_shards"`
+	NumberOfPendingTasks int32  `json:"number_of_pending_tasks"`
+}
+
+// AcknowledgedResponse is the typed response body shared by most PUT/POST
+// settings calls (cluster settings, index settings, templates).
+type AcknowledgedResponse struct {
+	Acknowledged bool `json:"acknowledged"`
+}
+
+// FlushResponse is the typed response body of a POST _flush/synced call.
+type FlushResponse struct {
+	Shards struct {
+		Total      int32 `json:"total"`
+		Successful int32 `json:"successful"`
+		Failed     int32 `json:"failed"`
+	} `json:"_shards"`
+}
+
+// IndexInfo is a single row of a GET _cat/indices response.
+type IndexInfo struct {
+	Health   string
+	Status   string
+	Index    string
+	Primary  int32
+	Replicas int32
+}
@@ -0,0 +1,233 @@
+package k8shandler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/openshift/elasticsearch-operator/pkg/esclient"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertReloader keeps an in-memory *tls.Config for a cluster's admin client
+// up to date with its backing CertStore, without the pod having to
+// restart to pick up a rotated Secret. It also mirrors every update to the
+// local cert directory at certLocalPath so any code that still reads
+// admin-ca/admin-cert/admin-key off disk stays in sync.
+type CertReloader struct {
+	name string
+
+	mu        sync.RWMutex
+	tlsConfig *tls.Config
+
+	subscribersMu sync.Mutex
+	subscribers   []chan struct{}
+}
+
+// NewCertReloader does an initial Get against store, builds the admin
+// tls.Config, writes it to the local cert directory, and then starts a
+// goroutine that applies every further store.Watch update the same way
+// until ctx is cancelled.
+func NewCertReloader(ctx context.Context, store CertStore, name string) (*CertReloader, error) {
+	r := &CertReloader{name: name}
+
+	bundle, err := store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.apply(bundle); err != nil {
+		return nil, err
+	}
+
+	updates, err := store.Watch(ctx, name)
+	if err != nil {
+		if err == ErrWatchUnsupported {
+			logrus.Debugf("certstore: driver does not support Watch for %v, admin certs will not hot-reload", name)
+			return r, nil
+		}
+		return nil, err
+	}
+
+	go func() {
+		for bundle := range updates {
+			if err := r.apply(bundle); err != nil {
+				logrus.Errorf("certreload: unable to apply rotated cert for %v: %v", name, err)
+				continue
+			}
+			logrus.Infof("certreload: reloaded admin certs for %v", name)
+			r.notifySubscribers()
+		}
+	}()
+
+	return r, nil
+}
+
+// adminCertReloaders caches one CertReloader per cluster so that the watch
+// loop started by NewCertReloader is only started once per cluster, and so
+// that repeated admin client construction (getOldClient is called on every
+// curlESServiceOldClient round trip) observes rotations instead of each
+// reading a stale TLSConfig off its own short-lived reloader.
+var adminCertReloaders sync.Map // map[string]*CertReloader
+
+// adminCertReloaderFor returns the shared CertReloader for clusterName's
+// admin certs in namespace, building it (and its backing CertStore from
+// ES_CERT_STORE) on first use.
+func adminCertReloaderFor(clusterName, namespace string, k8sClient client.Client) (*CertReloader, error) {
+	key := namespace + "/" + clusterName
+
+	if v, ok := adminCertReloaders.Load(key); ok {
+		return v.(*CertReloader), nil
+	}
+
+	store, err := NewCertStoreFromEnv(k8sClient, namespace)
+	if err != nil {
+		return nil, &AdminCertError{Cluster: clusterName, Namespace: namespace, Err: err}
+	}
+
+	reloader, err := NewCertReloader(context.Background(), store, clusterName)
+	if err != nil {
+		return nil, &AdminCertError{Cluster: clusterName, Namespace: namespace, Err: err}
+	}
+
+	actual, _ := adminCertReloaders.LoadOrStore(key, reloader)
+	return actual.(*CertReloader), nil
+}
+
+// AdminCertError wraps a failure to load or reload a cluster's admin certs,
+// so callers like Reconcile can tell "ES is unreachable because the admin
+// cert isn't ready yet" apart from other errors with errors.As and surface
+// it as its own status condition instead of a generic reconcile failure.
+type AdminCertError struct {
+	Cluster   string
+	Namespace string
+	Err       error
+}
+
+func (e *AdminCertError) Error() string {
+	return fmt.Sprintf("admin certs unavailable for %v/%v: %v", e.Namespace, e.Cluster, e.Err)
+}
+
+func (e *AdminCertError) Unwrap() error {
+	return e.Err
+}
+
+// TLSConfig returns the current admin tls.Config. The returned pointer is
+// replaced, not mutated, on reload, so callers should call TLSConfig()
+// again on the next request rather than caching the result.
+func (r *CertReloader) TLSConfig() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tlsConfig
+}
+
+// AsCertSource adapts r into an esclient.CertSource, so an esclient.Client
+// built with esclient.WithCertSource(reloader.AsCertSource()) picks up the
+// same rotations getOldClient does, instead of each maintaining its own
+// copy of the admin cert material.
+func (r *CertReloader) AsCertSource() esclient.CertSource {
+	return func() ([]tls.Certificate, *x509.CertPool, error) {
+		cfg := r.TLSConfig()
+		if cfg == nil {
+			return nil, nil, fmt.Errorf("certreload: no certs loaded yet for %v", r.name)
+		}
+		return cfg.Certificates, cfg.RootCAs, nil
+	}
+}
+
+// Subscribe returns a channel that receives an empty struct every time the
+// admin cert is reloaded, so a caller holding its own long-lived
+// http.Transport knows when to rebuild it.
+func (r *CertReloader) Subscribe() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	r.subscribersMu.Lock()
+	r.subscribers = append(r.subscribers, ch)
+	r.subscribersMu.Unlock()
+
+	return ch
+}
+
+func (r *CertReloader) notifySubscribers() {
+	r.subscribersMu.Lock()
+	defer r.subscribersMu.Unlock()
+
+	for _, ch := range r.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// subscriber hasn't drained the last notification yet
+		}
+	}
+}
+
+func (r *CertReloader) apply(bundle CertBundle) error {
+	if err := atomicWriteCertBundle(r.name, bundle); err != nil {
+		return err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(bundle.CA)
+
+	certificate, err := tls.X509KeyPair(bundle.Cert, bundle.Key)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{certificate},
+	}
+
+	r.mu.Lock()
+	r.tlsConfig = tlsConfig
+	r.mu.Unlock()
+
+	return nil
+}
+
+// atomicWriteCertBundle writes admin-ca/admin-cert/admin-key to
+// certLocalPath/<name>/ by writing each file to a temp path in the same
+// directory and renaming it into place, so a concurrent reader never sees
+// a partially-written file.
+func atomicWriteCertBundle(name string, bundle CertBundle) error {
+	dir := path.Join(certLocalPath, name)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		"admin-ca":   bundle.CA,
+		"admin-cert": bundle.Cert,
+		"admin-key":  bundle.Key,
+	}
+
+	for key, value := range files {
+		mode := os.FileMode(0644)
+		if key == "admin-key" {
+			mode = 0600
+		}
+
+		if err := atomicWriteFile(path.Join(dir, key), value, mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func atomicWriteFile(target string, data []byte, perm os.FileMode) error {
+	tmp := target + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, target)
+}
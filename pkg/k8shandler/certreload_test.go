@@ -0,0 +1,165 @@
+package k8shandler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeCertStore is a CertStore whose Get/Watch are driven entirely by the
+// test, so CertReloader's reload path can be exercised without a real
+// Kubernetes Secret or Vault server.
+type fakeCertStore struct {
+	initial CertBundle
+	updates chan CertBundle
+}
+
+func (s *fakeCertStore) Get(ctx context.Context, name string) (CertBundle, error) {
+	return s.initial, nil
+}
+
+func (s *fakeCertStore) Put(ctx context.Context, name string, bundle CertBundle) error {
+	return nil
+}
+
+func (s *fakeCertStore) Watch(ctx context.Context, name string) (<-chan CertBundle, error) {
+	return s.updates, nil
+}
+
+func selfSignedBundle(t *testing.T, commonName string) CertBundle {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:         true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return CertBundle{CA: certPEM, Cert: certPEM, Key: keyPEM}
+}
+
+// TestCertReloader_ReloadsOnStoreUpdate flips the backing store's cert
+// contents and asserts that TLSConfig() (what getOldClient hands to the
+// admin http.Client) reflects the new certificate on the next call, the
+// same contract subscribers like Subscribe() rely on to know when to
+// rebuild their own transport.
+func TestCertReloader_ReloadsOnStoreUpdate(t *testing.T) {
+	useScratchCertLocalPath(t)
+
+	initial := selfSignedBundle(t, "initial")
+	rotated := selfSignedBundle(t, "rotated")
+
+	store := &fakeCertStore{initial: initial, updates: make(chan CertBundle, 1)}
+
+	reloader, err := NewCertReloader(context.Background(), store, "mycluster")
+	if err != nil {
+		t.Fatalf("NewCertReloader returned error: %v", err)
+	}
+
+	before := reloader.TLSConfig()
+	if len(before.Certificates) != 1 {
+		t.Fatalf("expected 1 certificate before rotation, got %d", len(before.Certificates))
+	}
+
+	subscriber := reloader.Subscribe()
+	store.updates <- rotated
+
+	select {
+	case <-subscriber:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload notification")
+	}
+
+	after := reloader.TLSConfig()
+	if after == before {
+		t.Fatal("expected TLSConfig to be replaced, not reused, after reload")
+	}
+
+	beforeLeaf, err := x509.ParseCertificate(before.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("unable to parse pre-rotation leaf: %v", err)
+	}
+	afterLeaf, err := x509.ParseCertificate(after.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("unable to parse post-rotation leaf: %v", err)
+	}
+
+	if beforeLeaf.Subject.CommonName != "initial" {
+		t.Errorf("expected pre-rotation cert CN %q, got %q", "initial", beforeLeaf.Subject.CommonName)
+	}
+	if afterLeaf.Subject.CommonName != "rotated" {
+		t.Errorf("expected post-rotation cert CN %q, got %q", "rotated", afterLeaf.Subject.CommonName)
+	}
+}
+
+// TestAdminCertReloaderFor_CachesPerCluster asserts that a second call for
+// the same cluster/namespace reuses the CertReloader (and its watch loop)
+// created by the first call, which is what makes getOldClient see
+// TLSConfig() updates instead of re-reading a fresh copy from disk/Secret
+// on every admin request.
+func TestAdminCertReloaderFor_CachesPerCluster(t *testing.T) {
+	useScratchCertLocalPath(t)
+
+	key := "openshift-logging/mycluster"
+	t.Cleanup(func() { adminCertReloaders.Delete(key) })
+
+	bundle := selfSignedBundle(t, "cached")
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mycluster", Namespace: "openshift-logging"},
+		Data: map[string][]byte{
+			"admin-ca":   bundle.CA,
+			"admin-cert": bundle.Cert,
+			"admin-key":  bundle.Key,
+		},
+	}
+	scheme := runtime.NewScheme()
+	v1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	first, err := adminCertReloaderFor("mycluster", "openshift-logging", fakeClient)
+	if err != nil {
+		t.Fatalf("adminCertReloaderFor returned error: %v", err)
+	}
+
+	second, err := adminCertReloaderFor("mycluster", "openshift-logging", fakeClient)
+	if err != nil {
+		t.Fatalf("adminCertReloaderFor returned error: %v", err)
+	}
+
+	if first != second {
+		t.Fatal("expected second call to reuse the cached CertReloader")
+	}
+}
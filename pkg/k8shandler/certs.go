@@ -0,0 +1,96 @@
+package k8shandler
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resourceVersionFile records the ResourceVersion of the Secret that was
+// last materialized into a cluster's local cert directory, so a restarted
+// operator can tell a stale/missing directory apart from one that's
+// already up to date.
+const resourceVersionFile = ".resourceVersion"
+
+// RehydrateAdminCerts lists every Secret matching selector in namespace and,
+// for any whose local cert directory under certLocalPath is missing or
+// older than the Secret's current ResourceVersion, re-extracts
+// admin-ca/admin-cert/admin-key back to disk. Call this once at the start
+// of reconcile, before any code path that builds a TLS client from
+// certLocalPath, so a restarted operator pod doesn't have to wait for
+// certs to be regenerated (and cluster trust rotated) before admin API
+// calls start working again.
+//
+// selector is the caller's responsibility rather than a constant owned by
+// this package: the label actually stamped on an admin cert Secret is
+// decided wherever those Secrets are created (the CR reconcile loop, not
+// this package), and this snapshot doesn't include that code to confirm
+// it against. Pass the same selector used there, e.g.
+// client.MatchingLabels{"logging-infra": "elasticsearch"}.
+func RehydrateAdminCerts(ctx context.Context, k8sClient client.Client, namespace string, selector client.MatchingLabels) error {
+
+	secretList := &v1.SecretList{}
+	if err := k8sClient.List(ctx, secretList, client.InNamespace(namespace), selector); err != nil {
+		return err
+	}
+
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+
+		if !hasAdminCertKeys(secret) {
+			continue
+		}
+
+		if isLocalCertDirCurrent(secret) {
+			continue
+		}
+
+		logrus.Infof("Rehydrating admin certs for %v/%v from existing Secret", namespace, secret.Name)
+		if _, err := ExtractSecretToDir(ctx, k8sClient, namespace, secret.Name, path.Join(certLocalPath, secret.Name)); err != nil {
+			return fmt.Errorf("unable to rehydrate admin certs for %v/%v: %w", namespace, secret.Name, err)
+		}
+		writeResourceVersionMarker(secret)
+	}
+
+	return nil
+}
+
+func hasAdminCertKeys(secret *v1.Secret) bool {
+	for _, key := range []string{"admin-ca", "admin-cert", "admin-key"} {
+		if _, ok := secret.Data[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocalCertDirCurrent reports whether the local cert directory for
+// secret already holds the contents of this exact Secret revision.
+func isLocalCertDirCurrent(secret *v1.Secret) bool {
+	dir := path.Join(certLocalPath, secret.Name)
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return false
+	}
+
+	marker, err := ioutil.ReadFile(path.Join(dir, resourceVersionFile))
+	if err != nil {
+		return false
+	}
+
+	return string(marker) == secret.ResourceVersion
+}
+
+func writeResourceVersionMarker(secret *v1.Secret) {
+	dir := path.Join(certLocalPath, secret.Name)
+
+	if err := ioutil.WriteFile(path.Join(dir, resourceVersionFile), []byte(secret.ResourceVersion), 0644); err != nil {
+		logrus.Errorf("Error writing resource version marker for %v: %v", secret.Name, err)
+	}
+}
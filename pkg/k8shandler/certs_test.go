@@ -0,0 +1,136 @@
+package k8shandler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// adminCertSelector is the selector a caller would pass to
+// RehydrateAdminCerts in production; kept here as the value these tests
+// exercise against, not as a constant this package owns (see the
+// RehydrateAdminCerts doc comment).
+var adminCertSelector = client.MatchingLabels{"logging-infra": "elasticsearch"}
+
+func adminSecret(name, namespace, resourceVersion string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			ResourceVersion: resourceVersion,
+			Labels:          map[string]string{"logging-infra": "elasticsearch"},
+		},
+		Data: map[string][]byte{
+			"admin-ca":   []byte("ca-data"),
+			"admin-cert": []byte("cert-data"),
+			"admin-key":  []byte("key-data"),
+		},
+	}
+}
+
+// useScratchCertLocalPath points certLocalPath at a temp directory for the
+// duration of the test, restoring it (and cleaning up) on return.
+func useScratchCertLocalPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "certs-test")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+
+	original := certLocalPath
+	certLocalPath = dir + "/"
+	t.Cleanup(func() {
+		certLocalPath = original
+		os.RemoveAll(dir)
+	})
+
+	return dir
+}
+
+func TestRehydrateAdminCerts_MissingLocalDir(t *testing.T) {
+	dir := useScratchCertLocalPath(t)
+
+	secret := adminSecret("elasticsearch-mycluster", "openshift-logging", "1")
+	scheme := runtime.NewScheme()
+	v1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	if err := RehydrateAdminCerts(context.TODO(), fakeClient, "openshift-logging", adminCertSelector); err != nil {
+		t.Fatalf("RehydrateAdminCerts returned error: %v", err)
+	}
+
+	secretDir := path.Join(dir, secret.Name)
+	for _, file := range []string{"admin-ca", "admin-cert", "admin-key"} {
+		if _, err := os.Stat(path.Join(secretDir, file)); err != nil {
+			t.Errorf("expected %v to be materialized: %v", file, err)
+		}
+	}
+
+	marker, err := ioutil.ReadFile(path.Join(secretDir, resourceVersionFile))
+	if err != nil {
+		t.Fatalf("expected resource version marker to be written: %v", err)
+	}
+	if string(marker) != "1" {
+		t.Errorf("expected marker %q, got %q", "1", marker)
+	}
+}
+
+func TestRehydrateAdminCerts_DivergingContentReExtracts(t *testing.T) {
+	dir := useScratchCertLocalPath(t)
+
+	secret := adminSecret("elasticsearch-mycluster", "openshift-logging", "1")
+	scheme := runtime.NewScheme()
+	v1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	if err := RehydrateAdminCerts(context.TODO(), fakeClient, "openshift-logging", adminCertSelector); err != nil {
+		t.Fatalf("initial RehydrateAdminCerts returned error: %v", err)
+	}
+
+	secret.Data["admin-ca"] = []byte("rotated-ca-data")
+	secret.ResourceVersion = "2"
+	if err := fakeClient.Update(context.TODO(), secret); err != nil {
+		t.Fatalf("unable to update secret: %v", err)
+	}
+
+	if err := RehydrateAdminCerts(context.TODO(), fakeClient, "openshift-logging", adminCertSelector); err != nil {
+		t.Fatalf("second RehydrateAdminCerts returned error: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path.Join(dir, secret.Name, "admin-ca"))
+	if err != nil {
+		t.Fatalf("unable to read rehydrated admin-ca: %v", err)
+	}
+	if string(got) != "rotated-ca-data" {
+		t.Errorf("expected rehydrated admin-ca to reflect rotated Secret, got %q", got)
+	}
+}
+
+func TestRehydrateAdminCerts_SkipsSecretsMissingSelector(t *testing.T) {
+	dir := useScratchCertLocalPath(t)
+
+	unrelated := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "openshift-logging"},
+		Data:       map[string][]byte{"admin-ca": []byte("x"), "admin-cert": []byte("x"), "admin-key": []byte("x")},
+	}
+	scheme := runtime.NewScheme()
+	v1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(unrelated).Build()
+
+	if err := RehydrateAdminCerts(context.TODO(), fakeClient, "openshift-logging", adminCertSelector); err != nil {
+		t.Fatalf("RehydrateAdminCerts returned error: %v", err)
+	}
+
+	if _, err := os.Stat(path.Join(dir, unrelated.Name)); !os.IsNotExist(err) {
+		t.Errorf("expected unrelated Secret not to be materialized")
+	}
+}
@@ -0,0 +1,329 @@
+package k8shandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertBundle is the admin CA/cert/key triple a CertStore hands back,
+// keyed the same way the "admin-ca"/"admin-cert"/"admin-key" Secret data
+// always has been.
+type CertBundle struct {
+	CA   []byte
+	Cert []byte
+	Key  []byte
+}
+
+// ErrWatchUnsupported is returned by CertStore implementations that can't
+// notify on change (e.g. the one-shot file driver).
+var ErrWatchUnsupported = errors.New("certstore: Watch not supported by this driver")
+
+// CertStore abstracts where admin cert material for a managed cluster
+// lives. The existing Kubernetes Secret behavior becomes the "kubernetes"
+// driver; "file" and "vault" drivers let clusters keep admin PKI out of
+// etcd. Select a driver with the ES_CERT_STORE env var, e.g.
+// "kubernetes" (default), "file:///etc/elasticsearch-operator/certs", or
+// "vault://secret/data/es-admin".
+type CertStore interface {
+	Get(ctx context.Context, name string) (CertBundle, error)
+	Put(ctx context.Context, name string, bundle CertBundle) error
+	Watch(ctx context.Context, name string) (<-chan CertBundle, error)
+}
+
+// NewCertStoreFromEnv builds the CertStore selected by ES_CERT_STORE,
+// defaulting to the "kubernetes" driver used by the operator historically.
+func NewCertStoreFromEnv(k8sClient client.Client, namespace string) (CertStore, error) {
+	return NewCertStore(os.Getenv("ES_CERT_STORE"), k8sClient, namespace)
+}
+
+// NewCertStore builds the CertStore named by spec, one of:
+//
+//	""                                      -- kubernetes driver (default)
+//	"kubernetes"                            -- kubernetes driver
+//	"file://<dir>"                          -- file driver rooted at <dir>
+//	"vault://<mount>/<path>"                -- Vault KV v2 driver
+func NewCertStore(spec string, k8sClient client.Client, namespace string) (CertStore, error) {
+	switch {
+	case spec == "" || spec == "kubernetes":
+		return &kubernetesCertStore{k8sClient: k8sClient, namespace: namespace}, nil
+
+	case strings.HasPrefix(spec, "file://"):
+		return &fileCertStore{root: strings.TrimPrefix(spec, "file://")}, nil
+
+	case strings.HasPrefix(spec, "vault://"):
+		return newVaultCertStore(strings.TrimPrefix(spec, "vault://"))
+
+	default:
+		return nil, fmt.Errorf("certstore: unrecognized ES_CERT_STORE %q", spec)
+	}
+}
+
+// kubernetesCertStore is the existing admin-ca/admin-cert/admin-key Secret
+// behavior, exposed through the CertStore interface.
+type kubernetesCertStore struct {
+	k8sClient client.Client
+	namespace string
+}
+
+func (s *kubernetesCertStore) Get(ctx context.Context, name string) (CertBundle, error) {
+	secret := &v1.Secret{}
+	if err := s.k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: s.namespace}, secret); err != nil {
+		return CertBundle{}, err
+	}
+
+	return CertBundle{
+		CA:   secret.Data["admin-ca"],
+		Cert: secret.Data["admin-cert"],
+		Key:  secret.Data["admin-key"],
+	}, nil
+}
+
+func (s *kubernetesCertStore) Put(ctx context.Context, name string, bundle CertBundle) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.namespace,
+		},
+		Data: map[string][]byte{
+			"admin-ca":   bundle.CA,
+			"admin-cert": bundle.Cert,
+			"admin-key":  bundle.Key,
+		},
+	}
+
+	existing := &v1.Secret{}
+	err := s.k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: s.namespace}, existing)
+	if err == nil {
+		existing.Data = secret.Data
+		return s.k8sClient.Update(ctx, existing)
+	}
+
+	return s.k8sClient.Create(ctx, secret)
+}
+
+// watchPollInterval is how often driver Watch implementations re-check
+// their backing store for a change. A real Kubernetes informer would push
+// updates instead, but plumbing one through into this package is out of
+// scope here -- polling ResourceVersion is cheap and gives the same
+// external behavior.
+const watchPollInterval = 30 * time.Second
+
+func (s *kubernetesCertStore) Watch(ctx context.Context, name string) (<-chan CertBundle, error) {
+	secret := &v1.Secret{}
+	if err := s.k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: s.namespace}, secret); err != nil {
+		return nil, err
+	}
+
+	out := make(chan CertBundle)
+	go func() {
+		defer close(out)
+
+		lastResourceVersion := secret.ResourceVersion
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current := &v1.Secret{}
+				if err := s.k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: s.namespace}, current); err != nil {
+					logrus.Warnf("certstore: unable to poll Secret %v/%v for changes: %v", s.namespace, name, err)
+					continue
+				}
+
+				if current.ResourceVersion == lastResourceVersion {
+					continue
+				}
+				lastResourceVersion = current.ResourceVersion
+
+				select {
+				case out <- CertBundle{
+					CA:   current.Data["admin-ca"],
+					Cert: current.Data["admin-cert"],
+					Key:  current.Data["admin-key"],
+				}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// fileCertStore reads/writes admin-ca/admin-cert/admin-key as plain files
+// under root/<name>/, for operators that manage admin PKI themselves
+// outside of Kubernetes Secrets entirely.
+type fileCertStore struct {
+	root string
+}
+
+func (s *fileCertStore) dir(name string) string {
+	return filepath.Join(s.root, name)
+}
+
+func (s *fileCertStore) Get(ctx context.Context, name string) (CertBundle, error) {
+	dir := s.dir(name)
+
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "admin-ca"))
+	if err != nil {
+		return CertBundle{}, err
+	}
+
+	cert, err := ioutil.ReadFile(filepath.Join(dir, "admin-cert"))
+	if err != nil {
+		return CertBundle{}, err
+	}
+
+	key, err := ioutil.ReadFile(filepath.Join(dir, "admin-key"))
+	if err != nil {
+		return CertBundle{}, err
+	}
+
+	return CertBundle{CA: ca, Cert: cert, Key: key}, nil
+}
+
+func (s *fileCertStore) Put(ctx context.Context, name string, bundle CertBundle) error {
+	dir := s.dir(name)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "admin-ca"), bundle.CA, 0644); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "admin-cert"), bundle.Cert, 0644); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "admin-key"), bundle.Key, 0600)
+}
+
+func (s *fileCertStore) Watch(ctx context.Context, name string) (<-chan CertBundle, error) {
+	return nil, ErrWatchUnsupported
+}
+
+// vaultCertStore reads/writes a CertBundle as the "admin-ca"/"admin-cert"/
+// "admin-key" fields of a Vault KV v2 secret, addressed as
+// vault://<mount>/<path>. VAULT_ADDR and VAULT_TOKEN are read from the
+// environment, matching the standard Vault CLI/API conventions.
+type vaultCertStore struct {
+	addr       string
+	token      string
+	mountPath  string
+	httpClient *http.Client
+}
+
+func newVaultCertStore(mountPath string) (*vaultCertStore, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, errors.New("certstore: VAULT_ADDR must be set to use the vault driver")
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, errors.New("certstore: VAULT_TOKEN must be set to use the vault driver")
+	}
+
+	return &vaultCertStore{
+		addr:       strings.TrimSuffix(addr, "/"),
+		token:      token,
+		mountPath:  mountPath,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+func (s *vaultCertStore) url(name string) string {
+	return fmt.Sprintf("%s/v1/%s/%s", s.addr, s.mountPath, name)
+}
+
+func (s *vaultCertStore) Get(ctx context.Context, name string) (CertBundle, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url(name), nil)
+	if err != nil {
+		return CertBundle{}, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return CertBundle{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return CertBundle{}, fmt.Errorf("certstore: vault GET %s returned %d", s.url(name), resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return CertBundle{}, err
+	}
+
+	return CertBundle{
+		CA:   []byte(body.Data.Data["admin-ca"]),
+		Cert: []byte(body.Data.Data["admin-cert"]),
+		Key:  []byte(body.Data.Data["admin-key"]),
+	}, nil
+}
+
+func (s *vaultCertStore) Put(ctx context.Context, name string, bundle CertBundle) error {
+	payload := map[string]interface{}{
+		"data": map[string]string{
+			"admin-ca":   string(bundle.CA),
+			"admin-cert": string(bundle.Cert),
+			"admin-key":  string(bundle.Key),
+		},
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url(name), bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("certstore: vault POST %s returned %d", s.url(name), resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *vaultCertStore) Watch(ctx context.Context, name string) (<-chan CertBundle, error) {
+	return nil, ErrWatchUnsupported
+}
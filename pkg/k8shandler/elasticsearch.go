@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
+	goerrors "errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,13 +13,15 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	api "github.com/openshift/elasticsearch-operator/pkg/apis/logging/v1"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -28,9 +30,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const (
-	certLocalPath = "/tmp/"
-)
+// certLocalPath is a var, not a const, so tests can point it at a scratch
+// directory instead of writing through /tmp.
+var certLocalPath = "/tmp/"
 
 type esCurlStruct struct {
 	Method       string // use net/http constants https://golang.org/pkg/net/http/#pkg-constants
@@ -39,30 +41,29 @@ type esCurlStruct struct {
 	StatusCode   int
 	ResponseBody map[string]interface{}
 	Error        error
+
+	// Ctx carries the span created by the caller's reconcile (see
+	// StartReconcileSpan) so curlESService can record this call as a child
+	// span. Callers that don't set it get an untraced context.Background().
+	Ctx context.Context
 }
 
-func SetShardAllocation(clusterName, namespace string, state api.ShardAllocationState, client client.Client) (bool, error) {
+func SetShardAllocation(ctx context.Context, clusterName, namespace string, state api.ShardAllocationState, client client.Client) (bool, error) {
 
-	payload := &esCurlStruct{
-		Method:      http.MethodPut,
-		URI:         "_cluster/settings",
-		RequestBody: fmt.Sprintf("{%q:{%q:%q}}", "transient", "cluster.routing.allocation.enable", state),
+	esClient, err := getESClient(clusterName, namespace, client)
+	if err != nil {
+		return false, err
 	}
 
-	curlESService(clusterName, namespace, payload, client)
-
-	acknowledged := false
-	if acknowledgedBool, ok := payload.ResponseBody["acknowledged"].(bool); ok {
-		acknowledged = acknowledgedBool
-	}
-	return (payload.StatusCode == 200 && acknowledged), payload.Error
+	return esClient.SetShardAllocation(ctx, string(state))
 }
 
-func GetShardAllocation(clusterName, namespace string, client client.Client) (string, error) {
+func GetShardAllocation(ctx context.Context, clusterName, namespace string, client client.Client) (string, error) {
 
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cluster/settings",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -72,11 +73,12 @@ func GetShardAllocation(clusterName, namespace string, client client.Client) (st
 	return allocation, payload.Error
 }
 
-func GetNodeDiskUsage(clusterName, namespace, nodeName string, client client.Client) (string, float64, error) {
+func GetNodeDiskUsage(ctx context.Context, clusterName, namespace, nodeName string, client client.Client) (string, float64, error) {
 
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cat/nodes?h=name,du,dup",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -100,11 +102,12 @@ func GetNodeDiskUsage(clusterName, namespace, nodeName string, client client.Cli
 	return usage, percentUsage, payload.Error
 }
 
-func GetThresholdEnabled(clusterName, namespace string, client client.Client) (bool, error) {
+func GetThresholdEnabled(ctx context.Context, clusterName, namespace string, client client.Client) (bool, error) {
 
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cluster/settings?include_defaults=true",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -142,11 +145,12 @@ func GetThresholdEnabled(clusterName, namespace string, client client.Client) (b
 	return enabledBool, payload.Error
 }
 
-func GetDiskWatermarks(clusterName, namespace string, client client.Client) (interface{}, interface{}, error) {
+func GetDiskWatermarks(ctx context.Context, clusterName, namespace string, client client.Client) (interface{}, interface{}, error) {
 
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cluster/settings?include_defaults=true",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -313,12 +317,13 @@ func parseNodeDiskUsage(results string) map[string]interface{} {
 	return nodeDiskUsage
 }
 
-func SetMinMasterNodes(clusterName, namespace string, numberMasters int32, client client.Client) (bool, error) {
+func SetMinMasterNodes(ctx context.Context, clusterName, namespace string, numberMasters int32, client client.Client) (bool, error) {
 
 	payload := &esCurlStruct{
 		Method:      http.MethodPut,
 		URI:         "_cluster/settings",
 		RequestBody: fmt.Sprintf("{%q:{%q:%d}}", "persistent", "discovery.zen.minimum_master_nodes", numberMasters),
+		Ctx:         ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -331,11 +336,12 @@ func SetMinMasterNodes(clusterName, namespace string, numberMasters int32, clien
 	return (payload.StatusCode == 200 && acknowledged), payload.Error
 }
 
-func GetMinMasterNodes(clusterName, namespace string, client client.Client) (int32, error) {
+func GetMinMasterNodes(ctx context.Context, clusterName, namespace string, client client.Client) (int32, error) {
 
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cluster/settings",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -351,39 +357,37 @@ func GetMinMasterNodes(clusterName, namespace string, client client.Client) (int
 	return masterCount, payload.Error
 }
 
-func GetClusterHealth(clusterName, namespace string, client client.Client) (api.ClusterHealth, error) {
-
-	clusterHealth := api.ClusterHealth{}
+func GetClusterHealth(ctx context.Context, clusterName, namespace string, client client.Client) (api.ClusterHealth, error) {
 
-	payload := &esCurlStruct{
-		Method: http.MethodGet,
-		URI:    "_cluster/health",
+	esClient, err := getESClient(clusterName, namespace, client)
+	if err != nil {
+		return api.ClusterHealth{}, err
 	}
 
-	curlESService(clusterName, namespace, payload, client)
-
-	if payload.Error != nil {
-		return clusterHealth, payload.Error
+	health, err := esClient.ClusterHealth(ctx)
+	if err != nil {
+		return api.ClusterHealth{}, err
 	}
 
-	clusterHealth.Status = parseString("status", payload.ResponseBody)
-	clusterHealth.NumNodes = parseInt32("number_of_nodes", payload.ResponseBody)
-	clusterHealth.NumDataNodes = parseInt32("number_of_data_nodes", payload.ResponseBody)
-	clusterHealth.ActivePrimaryShards = parseInt32("active_primary_shards", payload.ResponseBody)
-	clusterHealth.ActiveShards = parseInt32("active_shards", payload.ResponseBody)
-	clusterHealth.RelocatingShards = parseInt32("relocating_shards", payload.ResponseBody)
-	clusterHealth.InitializingShards = parseInt32("initializing_shards", payload.ResponseBody)
-	clusterHealth.UnassignedShards = parseInt32("unassigned_shards", payload.ResponseBody)
-	clusterHealth.PendingTasks = parseInt32("number_of_pending_tasks", payload.ResponseBody)
-
-	return clusterHealth, nil
+	return api.ClusterHealth{
+		Status:              health.Status,
+		NumNodes:            health.NumberOfNodes,
+		NumDataNodes:        health.NumberOfDataNodes,
+		ActivePrimaryShards: health.ActivePrimaryShards,
+		ActiveShards:        health.ActiveShards,
+		RelocatingShards:    health.RelocatingShards,
+		InitializingShards:  health.InitializingShards,
+		UnassignedShards:    health.UnassignedShards,
+		PendingTasks:        health.NumberOfPendingTasks,
+	}, nil
 }
 
-func GetClusterHealthStatus(clusterName, namespace string, client client.Client) (string, error) {
+func GetClusterHealthStatus(ctx context.Context, clusterName, namespace string, client client.Client) (string, error) {
 
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cluster/health",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -398,11 +402,12 @@ func GetClusterHealthStatus(clusterName, namespace string, client client.Client)
 	return status, payload.Error
 }
 
-func GetClusterNodeCount(clusterName, namespace string, client client.Client) (int32, error) {
+func GetClusterNodeCount(ctx context.Context, clusterName, namespace string, client client.Client) (int32, error) {
 
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cluster/health",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -416,45 +421,36 @@ func GetClusterNodeCount(clusterName, namespace string, client client.Client) (i
 	return nodeCount, payload.Error
 }
 
-// TODO: also check that the number of shards in the response > 0?
-func DoSynchronizedFlush(clusterName, namespace string, client client.Client) (bool, error) {
-
-	payload := &esCurlStruct{
-		Method: http.MethodPost,
-		URI:    "_flush/synced",
-	}
-
-	curlESService(clusterName, namespace, payload, client)
+func DoSynchronizedFlush(ctx context.Context, clusterName, namespace string, client client.Client) (bool, error) {
 
-	failed := 0
-	if shards, ok := payload.ResponseBody["_shards"].(map[string]interface{}); ok {
-		if failedFload, ok := shards["failed"].(float64); ok {
-			failed = int(failedFload)
-		}
-	}
-
-	if payload.Error == nil && failed != 0 {
-		payload.Error = fmt.Errorf("Failed to flush %d shards in preparation for cluster restart", failed)
+	esClient, err := getESClient(clusterName, namespace, client)
+	if err != nil {
+		return false, err
 	}
 
-	return (payload.StatusCode == 200), payload.Error
+	return esClient.Flush(ctx)
 }
 
 // This will idempompotently update the index templates and update indices' replica count
-func UpdateReplicaCount(clusterName, namespace string, client client.Client, replicaCount int32) (bool, error) {
+func UpdateReplicaCount(ctx context.Context, clusterName, namespace string, client client.Client, replicaCount int32) (bool, error) {
 
-	if ok, _ := updateAllIndexTemplateReplicas(clusterName, namespace, client, replicaCount); ok {
-		if ok, _ = updateAllIndexReplicas(clusterName, namespace, client, replicaCount); ok {
-			return true, nil
-		}
+	ok, err := updateAllIndexTemplateReplicas(ctx, clusterName, namespace, client, replicaCount)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
 	}
 
-	return false, nil
+	return updateAllIndexReplicas(ctx, clusterName, namespace, client, replicaCount)
 }
 
-func updateAllIndexReplicas(clusterName, namespace string, client client.Client, replicaCount int32) (bool, error) {
+func updateAllIndexReplicas(ctx context.Context, clusterName, namespace string, client client.Client, replicaCount int32) (bool, error) {
 
-	indexHealth, _ := getIndexHealth(clusterName, namespace, client)
+	indexHealth, err := getIndexHealth(ctx, clusterName, namespace, "", client)
+	if err != nil {
+		return false, err
+	}
 
 	// get list of indices and call updateIndexReplicas for each one
 	for index, health := range indexHealth {
@@ -462,81 +458,51 @@ func updateAllIndexReplicas(clusterName, namespace string, client client.Client,
 		if parseInt32("replicas", health.(map[string]interface{})) != replicaCount {
 			// best effort initially?
 			logrus.Debugf("Updating %v from %d replicas to %d", index, parseInt32("replicas", health.(map[string]interface{})), replicaCount)
-			updateIndexReplicas(clusterName, namespace, client, index, replicaCount)
+			updateIndexReplicas(ctx, clusterName, namespace, client, index, replicaCount)
 		}
 	}
 
 	return true, nil
 }
 
-func getIndexHealth(clusterName, namespace string, client client.Client) (map[string]interface{}, error) {
-	payload := &esCurlStruct{
-		Method: http.MethodGet,
-		URI:    "_cat/indices?h=health,status,index,pri,rep",
-	}
+// getIndexHealth returns the same shape the legacy _cat/indices text
+// parsing produced (index name -> map with "health"/"status"/"primary"/
+// "replicas"), but sourced from esclient.Client.CatIndices so this and
+// curlESService-based callers don't each parse the tabular response their
+// own way. indexPattern is passed straight through to CatIndices -- pass ""
+// to fetch every index.
+func getIndexHealth(ctx context.Context, clusterName, namespace, indexPattern string, client client.Client) (map[string]interface{}, error) {
 
-	curlESService(clusterName, namespace, payload, client)
-
-	response := make(map[string]interface{})
-	if payload, ok := payload.ResponseBody["results"].(string); ok {
-		response = parseIndexHealth(payload)
+	esClient, err := getESClient(clusterName, namespace, client)
+	if err != nil {
+		return nil, err
 	}
 
-	return response, payload.Error
-}
-
-// ---
-// method: GET
-// uri: _cat/indices?h=health,status,index,pri,rep
-// requestbody: ""
-// statuscode: 200
-// responsebody:
-//   results: |
-//	 	green open .searchguard           1 0
-//		green open .kibana                1 0
-//		green open .operations.2019.07.01 1 0
-// error: null
-func parseIndexHealth(results string) map[string]interface{} {
-
-	indexHealth := make(map[string]interface{})
-
-	for _, result := range strings.Split(results, "\n") {
-
-		fields := []string{}
-		for _, val := range strings.Split(result, " ") {
-			if len(val) > 0 {
-				fields = append(fields, val)
-			}
-		}
-
-		if len(fields) == 5 {
-			primary, err := strconv.ParseFloat(fields[3], 64)
-			if err != nil {
-				primary = float64(-1)
-			}
-			replicas, err := strconv.ParseFloat(fields[4], 64)
-			if err != nil {
-				replicas = float64(-1)
-			}
+	rows, err := esClient.CatIndices(ctx, indexPattern)
+	if err != nil {
+		return nil, err
+	}
 
-			indexHealth[fields[2]] = map[string]interface{}{
-				"health":   fields[0],
-				"status":   fields[1],
-				"primary":  primary,
-				"replicas": replicas,
-			}
+	indexHealth := make(map[string]interface{}, len(rows))
+	for _, row := range rows {
+		indexHealth[row.Index] = map[string]interface{}{
+			"health":   row.Health,
+			"status":   row.Status,
+			"primary":  float64(row.Primary),
+			"replicas": float64(row.Replicas),
 		}
 	}
 
-	return indexHealth
+	return indexHealth, nil
 }
 
-func updateAllIndexTemplateReplicas(clusterName, namespace string, client client.Client, replicaCount int32) (bool, error) {
+func updateAllIndexTemplateReplicas(ctx context.Context, clusterName, namespace string, client client.Client, replicaCount int32) (bool, error) {
 
 	// get list of all common.* index templates and update their replica count for each one
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    "_cat/templates/common.*",
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -559,18 +525,19 @@ func updateAllIndexTemplateReplicas(clusterName, namespace string, client client
 	}
 
 	for _, template := range commonTemplates {
-		updateIndexTemplateReplicas(clusterName, namespace, client, template, replicaCount)
+		updateIndexTemplateReplicas(ctx, clusterName, namespace, client, template, replicaCount)
 	}
 
 	return true, nil
 }
 
-func updateIndexTemplateReplicas(clusterName, namespace string, client client.Client, templateName string, replicaCount int32) (bool, error) {
+func updateIndexTemplateReplicas(ctx context.Context, clusterName, namespace string, client client.Client, templateName string, replicaCount int32) (bool, error) {
 
 	// get the index template and then update the replica and put it
 	payload := &esCurlStruct{
 		Method: http.MethodGet,
 		URI:    fmt.Sprintf("_template/%s", templateName),
+		Ctx:    ctx,
 	}
 
 	curlESService(clusterName, namespace, payload, client)
@@ -590,6 +557,7 @@ func updateIndexTemplateReplicas(clusterName, namespace string, client client.Cl
 						Method:      http.MethodPut,
 						URI:         fmt.Sprintf("_template/%s", templateName),
 						RequestBody: string(templateJson),
+						Ctx:         ctx,
 					}
 
 					curlESService(clusterName, namespace, payload, client)
@@ -607,20 +575,14 @@ func updateIndexTemplateReplicas(clusterName, namespace string, client client.Cl
 	return false, payload.Error
 }
 
-func updateIndexReplicas(clusterName, namespace string, client client.Client, index string, replicaCount int32) (bool, error) {
-	payload := &esCurlStruct{
-		Method:      http.MethodPut,
-		URI:         fmt.Sprintf("%s/_settings", index),
-		RequestBody: fmt.Sprintf("{%q:\"%d\"}}", "index.number_of_replicas", replicaCount),
-	}
-
-	curlESService(clusterName, namespace, payload, client)
+func updateIndexReplicas(ctx context.Context, clusterName, namespace string, client client.Client, index string, replicaCount int32) (bool, error) {
 
-	acknowledged := false
-	if acknowledgedBool, ok := payload.ResponseBody["acknowledged"].(bool); ok {
-		acknowledged = acknowledgedBool
+	esClient, err := getESClient(clusterName, namespace, client)
+	if err != nil {
+		return false, err
 	}
-	return (payload.StatusCode == 200 && acknowledged), payload.Error
+
+	return esClient.UpdateIndexReplicas(ctx, index, replicaCount)
 }
 
 func ensureTokenHeader(header http.Header) http.Header {
@@ -661,18 +623,36 @@ func readSAToken(tokenFile string) (string, bool) {
 //  it will also return the http and string response
 func curlESService(clusterName, namespace string, payload *esCurlStruct, client client.Client) {
 
+	start := time.Now()
+	defer func() {
+		observeESRequest(clusterName, namespace, payload.Method, payload.URI, payload.StatusCode, time.Since(start))
+	}()
+
+	ctx := payload.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, span := esTracer().Start(ctx, fmt.Sprintf("ES %s %s", payload.Method, payload.URI))
+	defer span.End()
+
 	urlString := fmt.Sprintf("https://%s.%s.svc:9200/%s", clusterName, namespace, payload.URI)
-	urlURL, err := url.Parse(urlString)
 
+	request, err := http.NewRequestWithContext(ctx, payload.Method, urlString, nil)
 	if err != nil {
 		logrus.Warnf("Unable to parse URL %v: %v", urlString, err)
+		span.RecordError(err)
 		return
 	}
 
-	request := &http.Request{
-		Method: payload.Method,
-		URL:    urlURL,
-	}
+	span.SetAttributes(
+		attribute.String("http.method", payload.Method),
+		attribute.String("http.url", urlString),
+		attribute.String("es.cluster", clusterName),
+		// curlESService never retries itself -- see esclient.Client for the
+		// retrying code path, which records its own attempt count.
+		attribute.Int("es.retry", 0),
+	)
 
 	switch payload.Method {
 	case http.MethodGet:
@@ -720,12 +700,18 @@ func curlESService(clusterName, namespace string, payload *esCurlStruct, client
 
 			// Not sure why, but just trying to reuse the request with the old client
 			// resulted in a 400 every time. Doing it this way got a 200 response as expected.
+			span.SetAttributes(attribute.Int("es.status_code", resp.StatusCode))
 			curlESServiceOldClient(clusterName, namespace, payload, client)
 			return
 		}
 
 		payload.StatusCode = resp.StatusCode
 		payload.ResponseBody = getMapFromBody(resp.Body)
+		span.SetAttributes(attribute.Int("es.status_code", resp.StatusCode))
+	}
+
+	if err != nil {
+		span.RecordError(err)
 	}
 
 	payload.Error = err
@@ -776,7 +762,12 @@ func curlESServiceOldClient(clusterName, namespace string, payload *esCurlStruct
 		return
 	}
 
-	httpClient := getOldClient(clusterName, namespace, client)
+	httpClient, err := getOldClient(clusterName, namespace, client)
+	if err != nil {
+		payload.Error = err
+		return
+	}
+
 	resp, err := httpClient.Do(request)
 
 	if resp != nil {
@@ -787,21 +778,6 @@ func curlESServiceOldClient(clusterName, namespace string, payload *esCurlStruct
 	payload.Error = err
 }
 
-func getRootCA(clusterName, namespace string) *x509.CertPool {
-	certPool := x509.NewCertPool()
-
-	// load cert into []byte
-	caPem, err := ioutil.ReadFile(path.Join(certLocalPath, clusterName, "admin-ca"))
-	if err != nil {
-		logrus.Errorf("Unable to read file to get contents: %v", err)
-		return nil
-	}
-
-	certPool.AppendCertsFromPEM(caPem)
-
-	return certPool
-}
-
 func getMapFromBody(body io.ReadCloser) map[string]interface{} {
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(body)
@@ -816,25 +792,15 @@ func getMapFromBody(body io.ReadCloser) map[string]interface{} {
 	return results
 }
 
-func getClientCertificates(clusterName, namespace string) []tls.Certificate {
-	certificate, err := tls.LoadX509KeyPair(
-		path.Join(certLocalPath, clusterName, "admin-cert"),
-		path.Join(certLocalPath, clusterName, "admin-key"),
-	)
-	if err != nil {
-		return []tls.Certificate{}
-	}
-
-	return []tls.Certificate{
-		certificate,
-	}
-}
-
 func getClient(clusterName, namespace string, client client.Client) *http.Client {
 
 	// http.Transport sourced from go 1.10.7
 	return &http.Client{
-		Transport: &http.Transport{
+		// otelhttp.NewTransport turns every round trip made through this
+		// client into a child span of the request's context, propagating
+		// the W3C traceparent header so Elasticsearch can log it via
+		// X-Opaque-Id.
+		Transport: otelhttp.NewTransport(&http.Transport{
 			Proxy: http.ProxyFromEnvironment,
 			DialContext: (&net.Dialer{
 				Timeout:   30 * time.Second,
@@ -850,14 +816,20 @@ func getClient(clusterName, namespace string, client client.Client) *http.Client
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true,
 			},
-		},
+		}),
 	}
 }
 
-func getOldClient(clusterName, namespace string, client client.Client) *http.Client {
+func getOldClient(clusterName, namespace string, client client.Client) (*http.Client, error) {
 
-	// get the contents of the secret
-	extractSecret(clusterName, namespace, client)
+	// Reuse (or start) the watch-driven reloader for this cluster's admin
+	// certs instead of reading admin-ca/admin-cert/admin-key off disk on
+	// every call -- this is what makes a Secret rotation actually take
+	// effect without the operator pod restarting.
+	reloader, err := adminCertReloaderFor(clusterName, namespace, client)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load admin certs for %v/%v: %w", namespace, clusterName, err)
+	}
 
 	// http.Transport sourced from go 1.10.7
 	return &http.Client{
@@ -872,16 +844,54 @@ func getOldClient(clusterName, namespace string, client client.Client) *http.Cli
 			IdleConnTimeout:       90 * time.Second,
 			TLSHandshakeTimeout:   10 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: false,
-				RootCAs:            getRootCA(clusterName, namespace),
-				Certificates:       getClientCertificates(clusterName, namespace),
-			},
+			TLSClientConfig:       reloader.TLSConfig(),
 		},
-	}
+	}, nil
 }
 
-func extractSecret(secretName, namespace string, client client.Client) {
+// CertPaths is the on-disk location of each file ExtractSecretToDir wrote.
+type CertPaths struct {
+	CA   string
+	Cert string
+	Key  string
+}
+
+// ErrSecretNotFound is returned by ExtractSecretToDir when the named
+// Secret doesn't exist.
+var ErrSecretNotFound = goerrors.New("extractsecret: secret not found")
+
+// ErrMissingKey is returned by ExtractSecretToDir when the Secret exists
+// but is missing one of admin-ca/admin-cert/admin-key.
+type ErrMissingKey struct {
+	Key string
+}
+
+func (e ErrMissingKey) Error() string {
+	return fmt.Sprintf("extractsecret: secret data key %q not found", e.Key)
+}
+
+// ErrWrite is returned by ExtractSecretToDir when a cert file could not be
+// written to dir.
+type ErrWrite struct {
+	Path string
+	Err  error
+}
+
+func (e ErrWrite) Error() string {
+	return fmt.Sprintf("extractsecret: writing %q: %v", e.Path, e.Err)
+}
+
+func (e ErrWrite) Unwrap() error {
+	return e.Err
+}
+
+// ExtractSecretToDir fetches the admin-ca/admin-cert/admin-key Secret
+// secretName and writes each key to dir, creating dir with mode 0700 if
+// needed. admin-key is written 0600 since it's private material; the
+// others are written 0644. It returns as soon as it hits a failure rather
+// than logging and continuing, so callers always know whether dir is
+// fully populated before using it to build a TLS client.
+func ExtractSecretToDir(ctx context.Context, client client.Client, namespace, secretName, dir string) (CertPaths, error) {
 	secret := &v1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Secret",
@@ -892,37 +902,43 @@ func extractSecret(secretName, namespace string, client client.Client) {
 			Namespace: namespace,
 		},
 	}
-	if err := client.Get(context.TODO(), types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, secret); err != nil {
+
+	if err := client.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, secret); err != nil {
 		if errors.IsNotFound(err) {
-			//return err
-			logrus.Errorf("Unable to find secret %v: %v", secretName, err)
+			return CertPaths{}, ErrSecretNotFound
 		}
-
-		logrus.Errorf("Error reading secret %v: %v", secretName, err)
-		//return fmt.Errorf("Unable to extract secret to file: %v", secretName, err)
+		return CertPaths{}, fmt.Errorf("extractsecret: reading secret %v: %w", secretName, err)
 	}
 
-	// make sure that the dir === secretName exists
-	if _, err := os.Stat(path.Join(certLocalPath, secretName)); os.IsNotExist(err) {
-		err = os.MkdirAll(path.Join(certLocalPath, secretName), 0755)
-		if err != nil {
-			logrus.Errorf("Error creating dir %v: %v", path.Join(certLocalPath, secretName), err)
-		}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return CertPaths{}, ErrWrite{Path: dir, Err: err}
 	}
 
-	for _, key := range []string{"admin-ca", "admin-cert", "admin-key"} {
+	paths := CertPaths{
+		CA:   filepath.Join(dir, "admin-ca"),
+		Cert: filepath.Join(dir, "admin-cert"),
+		Key:  filepath.Join(dir, "admin-key"),
+	}
 
+	for key, target := range map[string]string{
+		"admin-ca":   paths.CA,
+		"admin-cert": paths.Cert,
+		"admin-key":  paths.Key,
+	} {
 		value, ok := secret.Data[key]
-
-		// check to see if the map value exists
 		if !ok {
-			logrus.Errorf("Error secret key %v not found", key)
-			//return fmt.Errorf("No secret data \"%s\" found", key)
+			return CertPaths{}, ErrMissingKey{Key: key}
+		}
+
+		mode := os.FileMode(0644)
+		if key == "admin-key" {
+			mode = 0600
 		}
 
-		if err := ioutil.WriteFile(path.Join(certLocalPath, secretName, key), value, 0644); err != nil {
-			//return fmt.Errorf("Unable to write to working dir: %v", err)
-			logrus.Errorf("Error writing %v to %v: %v", value, path.Join(certLocalPath, secretName, key), err)
+		if err := ioutil.WriteFile(target, value, mode); err != nil {
+			return CertPaths{}, ErrWrite{Path: target, Err: err}
 		}
 	}
+
+	return paths, nil
 }
@@ -0,0 +1,42 @@
+package k8shandler
+
+import (
+	"sync"
+
+	"github.com/openshift/elasticsearch-operator/pkg/esclient"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// esClients caches one esclient.Client per managed cluster so that its
+// connection pool (and the transport rebuilds driven by its CertSource)
+// are shared across calls instead of a new client -- and a new TCP
+// handshake -- being built on every SetShardAllocation/GetClusterHealth/
+// etc. invocation.
+var esClients sync.Map // map[string]*esclient.Client
+
+// getESClient returns the shared esclient.Client for clusterName in
+// namespace, wired to reload its admin mTLS material from the same
+// CertReloader getOldClient uses.
+func getESClient(clusterName, namespace string, k8sClient client.Client) (*esclient.Client, error) {
+	key := namespace + "/" + clusterName
+
+	if v, ok := esClients.Load(key); ok {
+		return v.(*esclient.Client), nil
+	}
+
+	reloader, err := adminCertReloaderFor(clusterName, namespace, k8sClient)
+	if err != nil {
+		return nil, err
+	}
+
+	c := esclient.New(clusterName, namespace,
+		esclient.WithCertSource(reloader.AsCertSource()),
+		esclient.WithMetrics(esclient.DefaultRegistry),
+	)
+
+	actual, loaded := esClients.LoadOrStore(key, c)
+	if loaded {
+		c.Close()
+	}
+	return actual.(*esclient.Client), nil
+}
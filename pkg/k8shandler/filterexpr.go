@@ -0,0 +1,270 @@
+package k8shandler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// filterExpr is the parsed form of a small boolean predicate language used
+// to select a subset of _cat/* rows without fetching-then-filtering on the
+// caller's side, e.g.:
+//
+//	health == "red" and replicas < 2
+//	index matches "^app-.*" and pri > 5
+//
+// Grammar (lowest to highest precedence):
+//
+//	expr    := or
+//	or      := and ("or" and)*
+//	and     := unary ("and" unary)*
+//	unary   := "not" unary | compare
+//	compare := IDENT ("==" | "!=" | "<" | "<=" | ">" | ">=" | "matches") literal | "(" or ")"
+//	literal := STRING | NUMBER
+type filterExpr interface {
+	eval(row map[string]interface{}) bool
+}
+
+type andExpr struct{ left, right filterExpr }
+type orExpr struct{ left, right filterExpr }
+type notExpr struct{ expr filterExpr }
+
+type compareExpr struct {
+	field string
+	op    string
+	value string
+}
+
+func (e *andExpr) eval(row map[string]interface{}) bool { return e.left.eval(row) && e.right.eval(row) }
+func (e *orExpr) eval(row map[string]interface{}) bool  { return e.left.eval(row) || e.right.eval(row) }
+func (e *notExpr) eval(row map[string]interface{}) bool { return !e.expr.eval(row) }
+
+func (e *compareExpr) eval(row map[string]interface{}) bool {
+	actual, ok := row[e.field]
+	if !ok {
+		return false
+	}
+
+	if e.op == "matches" {
+		re, err := regexp.Compile(e.value)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(fmt.Sprintf("%v", actual))
+	}
+
+	// try numeric comparison first, fall back to string equality/inequality
+	actualFloat, actualIsNum := toFloat(actual)
+	valueFloat, valueErr := strconv.ParseFloat(e.value, 64)
+
+	if actualIsNum && valueErr == nil {
+		switch e.op {
+		case "==":
+			return actualFloat == valueFloat
+		case "!=":
+			return actualFloat != valueFloat
+		case "<":
+			return actualFloat < valueFloat
+		case "<=":
+			return actualFloat <= valueFloat
+		case ">":
+			return actualFloat > valueFloat
+		case ">=":
+			return actualFloat >= valueFloat
+		}
+	}
+
+	actualString := fmt.Sprintf("%v", actual)
+	switch e.op {
+	case "==":
+		return actualString == e.value
+	case "!=":
+		return actualString != e.value
+	default:
+		return false
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// parseFilter tokenizes and parses a filter expression into an evaluable
+// filterExpr. An empty expression matches every row.
+func parseFilter(expr string) (filterExpr, error) {
+	if strings.TrimSpace(expr) == "" {
+		return matchAll{}, nil
+	}
+
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	result, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token %q", p.tokens[p.pos])
+	}
+
+	return result, nil
+}
+
+type matchAll struct{}
+
+func (matchAll) eval(map[string]interface{}) bool { return true }
+
+func tokenizeFilter(expr string) ([]string, error) {
+	var tokens []string
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case runes[i] == ' ' || runes[i] == '\t':
+			i++
+		case runes[i] == '(' || runes[i] == ')':
+			tokens = append(tokens, string(runes[i]))
+			i++
+		case runes[i] == '"':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string literal starting at %d", i)
+			}
+			tokens = append(tokens, string(runes[i+1:j]))
+			i = j + 1
+		case strings.ContainsRune("=!<>", runes[i]):
+			j := i + 1
+			if j < len(runes) && runes[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			j := i
+			for j < len(runes) && runes[j] != ' ' && runes[j] != '\t' && runes[j] != '(' && runes[j] != ')' {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "or" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "and" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.peek() == "not" {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr}, nil
+	}
+
+	return p.parseCompare()
+}
+
+func (p *filterParser) parseCompare() (filterExpr, error) {
+	if p.peek() == "(" {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("expected closing ')'")
+		}
+		return expr, nil
+	}
+
+	field := p.next()
+	if field == "" {
+		return nil, fmt.Errorf("expected field name")
+	}
+
+	op := p.next()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "matches":
+	default:
+		return nil, fmt.Errorf("unexpected operator %q", op)
+	}
+
+	value := p.next()
+	if value == "" {
+		return nil, fmt.Errorf("expected value after operator %q", op)
+	}
+
+	return &compareExpr{field: field, op: op, value: value}, nil
+}
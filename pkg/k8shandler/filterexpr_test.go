@@ -0,0 +1,107 @@
+package k8shandler
+
+import "testing"
+
+func evalFilter(t *testing.T, expr string, row map[string]interface{}) bool {
+	t.Helper()
+
+	predicate, err := parseFilter(expr)
+	if err != nil {
+		t.Fatalf("parseFilter(%q) returned error: %v", expr, err)
+	}
+
+	return predicate.eval(row)
+}
+
+func TestParseFilter_EmptyMatchesEverything(t *testing.T) {
+	if !evalFilter(t, "", map[string]interface{}{"health": "red"}) {
+		t.Fatal("empty filter should match every row")
+	}
+}
+
+func TestParseFilter_NumericComparison(t *testing.T) {
+	row := map[string]interface{}{"replicas": float64(1)}
+
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"replicas < 2", true},
+		{"replicas <= 1", true},
+		{"replicas > 2", false},
+		{"replicas >= 1", true},
+		{"replicas == 1", true},
+		{"replicas != 1", false},
+	}
+
+	for _, c := range cases {
+		if got := evalFilter(t, c.expr, row); got != c.want {
+			t.Errorf("%q: got %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestParseFilter_StringComparison(t *testing.T) {
+	row := map[string]interface{}{"health": "red"}
+
+	if !evalFilter(t, `health == "red"`, row) {
+		t.Error(`health == "red" should match`)
+	}
+	if evalFilter(t, `health == "green"`, row) {
+		t.Error(`health == "green" should not match`)
+	}
+	if !evalFilter(t, `health != "green"`, row) {
+		t.Error(`health != "green" should match`)
+	}
+}
+
+func TestParseFilter_Matches(t *testing.T) {
+	row := map[string]interface{}{"index": "app-logs-2024.01.01"}
+
+	if !evalFilter(t, `index matches "^app-.*"`, row) {
+		t.Error("expected index to match ^app-.*")
+	}
+	if evalFilter(t, `index matches "^infra-.*"`, row) {
+		t.Error("expected index not to match ^infra-.*")
+	}
+}
+
+func TestParseFilter_AndOrNot(t *testing.T) {
+	row := map[string]interface{}{"health": "red", "replicas": float64(1)}
+
+	if !evalFilter(t, `health == "red" and replicas < 2`, row) {
+		t.Error("and expression should match")
+	}
+	if evalFilter(t, `health == "green" and replicas < 2`, row) {
+		t.Error("and expression should not match")
+	}
+	if !evalFilter(t, `health == "green" or replicas < 2`, row) {
+		t.Error("or expression should match")
+	}
+	if !evalFilter(t, `not health == "green"`, row) {
+		t.Error("not expression should match")
+	}
+}
+
+func TestParseFilter_Parentheses(t *testing.T) {
+	row := map[string]interface{}{"health": "red", "replicas": float64(3)}
+
+	if !evalFilter(t, `(health == "red" or health == "yellow") and replicas >= 2`, row) {
+		t.Error("parenthesized expression should match")
+	}
+	if evalFilter(t, `(health == "green" or health == "yellow") and replicas >= 2`, row) {
+		t.Error("parenthesized expression should not match")
+	}
+}
+
+func TestParseFilter_UnterminatedStringIsError(t *testing.T) {
+	if _, err := parseFilter(`health == "red`); err == nil {
+		t.Fatal("expected error for unterminated string literal")
+	}
+}
+
+func TestParseFilter_UnexpectedOperatorIsError(t *testing.T) {
+	if _, err := parseFilter(`health ~ "red"`); err == nil {
+		t.Fatal("expected error for unexpected operator")
+	}
+}
@@ -0,0 +1,102 @@
+package k8shandler
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IndexInfo is a typed row of a filtered _cat/indices response.
+type IndexInfo struct {
+	Index    string
+	Health   string
+	Status   string
+	Primary  int32
+	Replicas int32
+}
+
+// ListIndices returns the indices on clusterName matching filter, a
+// predicate expression evaluated over the same fields getIndexHealth
+// already parses out of _cat/indices (see parseFilter for the grammar). An
+// empty filter returns every index.
+//
+// Most of parseFilter's grammar (regex "matches", "<"/">"-style ranges,
+// "or"/"not") has no equivalent in _cat/indices' own query parameters, so
+// the general case is still evaluated client-side against every row
+// getIndexHealth returns. The one shape that does map cleanly is an
+// "index" equality test: _cat/indices/<name> accepts an index name or
+// pattern directly, so indexPatternFromFilter pulls an `index == "..."`
+// comparison anywhere in a top-level "and" chain (if present) and uses it
+// to narrow the _cat/indices request itself -- the dominant case callers
+// hit when they already know the index they want. filter is still
+// evaluated against the (now smaller) result afterward, so this is purely
+// a round-trip/payload optimization, not a change in which rows match.
+func ListIndices(ctx context.Context, clusterName, namespace string, filter string, k8sClient client.Client) ([]IndexInfo, error) {
+
+	predicate, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	indexPattern, _ := indexPatternFromFilter(predicate)
+
+	indexHealth, err := getIndexHealth(ctx, clusterName, namespace, indexPattern, k8sClient)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make([]IndexInfo, 0, len(indexHealth))
+	for index, healthValue := range indexHealth {
+		row, ok := healthValue.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// parseFilter's field names line up with parseIndexHealth's map
+		// keys, plus "index" itself which isn't stored in the row.
+		evalRow := make(map[string]interface{}, len(row)+1)
+		for k, v := range row {
+			evalRow[k] = v
+		}
+		evalRow["index"] = index
+		evalRow["pri"] = row["primary"]
+		evalRow["rep"] = row["replicas"]
+
+		if !predicate.eval(evalRow) {
+			continue
+		}
+
+		indices = append(indices, IndexInfo{
+			Index:    index,
+			Health:   parseString("health", row),
+			Status:   parseString("status", row),
+			Primary:  parseInt32("primary", row),
+			Replicas: parseInt32("replicas", row),
+		})
+	}
+
+	return indices, nil
+}
+
+// indexPatternFromFilter looks for an `index == "..."` comparison anywhere
+// in a top-level "and" chain of expr and returns its literal value, so the
+// caller can narrow a _cat/indices request to that pattern instead of
+// fetching every index. It deliberately does not look inside "or"/"not" --
+// either of those could match rows the literal excludes, so pushing it down
+// there would silently drop results rather than just fetch more than
+// strictly necessary.
+func indexPatternFromFilter(expr filterExpr) (string, bool) {
+	switch e := expr.(type) {
+	case *compareExpr:
+		if e.field == "index" && e.op == "==" {
+			return e.value, true
+		}
+	case *andExpr:
+		if pattern, ok := indexPatternFromFilter(e.left); ok {
+			return pattern, true
+		}
+		return indexPatternFromFilter(e.right)
+	}
+
+	return "", false
+}
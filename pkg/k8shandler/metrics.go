@@ -0,0 +1,97 @@
+package k8shandler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// esRequestDuration instruments curlESService, the legacy request path
+// that esclient.Client (instrumented via esclient.WithMetrics) doesn't
+// cover. uriTemplate is always the bounded template from uriTemplateFor,
+// never the literal payload.URI, so cardinality doesn't grow with every
+// distinct index/template name the operator touches.
+var esRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "elasticsearch_operator",
+	Subsystem: "k8shandler",
+	Name:      "es_request_duration_seconds",
+	Help:      "Duration of curlESService calls to Elasticsearch.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"cluster", "namespace", "method", "uri_template", "status"})
+
+// DefaultRegistry is a ready-to-use Registerer for callers that don't
+// maintain their own, e.g. k8shandler.RegisterMetrics(k8shandler.DefaultRegistry).
+// MetricsHandler serves exactly this registry, so a reg passed to
+// RegisterMetrics other than DefaultRegistry needs its own handler --
+// mirrors the esclient.DefaultRegistry/esclient.MetricsHandler contract.
+var DefaultRegistry = prometheus.NewRegistry()
+
+// RegisterMetrics registers this package's Prometheus collectors against
+// reg. Call once, from wherever the operator's manager wires up its
+// /metrics endpoint -- this snapshot doesn't include that entrypoint, so
+// there's nothing here to call it automatically.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(esRequestDuration)
+}
+
+// MetricsHandler exposes DefaultRegistry in the standard Prometheus text
+// exposition format, for mounting at /metrics.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(DefaultRegistry, promhttp.HandlerOpts{})
+}
+
+func observeESRequest(clusterName, namespace, method, uri string, status int, duration time.Duration) {
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = http.StatusText(status)
+	}
+
+	esRequestDuration.WithLabelValues(clusterName, namespace, method, uriTemplateFor(uri), statusLabel).Observe(duration.Seconds())
+}
+
+// uriTemplateFor canonicalizes uri (as built for esCurlStruct.URI) into a
+// bounded-cardinality template suitable for a Prometheus label, replacing
+// every dynamic name segment (template/repository/snapshot/policy name)
+// with a fixed placeholder -- the same role the explicit template string
+// callers pass to esclient.Client.request plays for the typed client. It
+// walks path segments rather than matching a single regex so multi-segment
+// shapes like `_snapshot/<repo>/<snapshot>/_restore` get every dynamic
+// segment replaced, not just the first.
+func uriTemplateFor(uri string) string {
+	path := uri
+	if idx := strings.Index(uri, "?"); idx != -1 {
+		path = uri[:idx]
+	}
+
+	segments := strings.Split(path, "/")
+
+	switch {
+	case len(segments) >= 2 && segments[0] == "_template":
+		return "_template/{name}"
+
+	case len(segments) >= 3 && segments[0] == "_slm" && segments[1] == "policy":
+		return "_slm/policy/{name}"
+
+	case len(segments) >= 2 && segments[0] == "_snapshot":
+		switch len(segments) {
+		case 2:
+			return "_snapshot/{repo}"
+		case 3:
+			if segments[2] == "_all" {
+				return "_snapshot/{repo}/_all"
+			}
+			return "_snapshot/{repo}/{name}"
+		default:
+			return "_snapshot/{repo}/{name}/" + strings.Join(segments[3:], "/")
+		}
+	}
+
+	if strings.HasSuffix(path, "/_settings") {
+		return "{index}/_settings"
+	}
+
+	return path
+}
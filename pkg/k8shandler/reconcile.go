@@ -0,0 +1,102 @@
+package k8shandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	api "github.com/openshift/elasticsearch-operator/pkg/apis/logging/v1"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// conditionAdminCertUnavailable is set on the Elasticsearch CR's
+// status.conditions when Reconcile can't reach ES because its admin certs
+// aren't ready yet (see AdminCertError), instead of letting that failure
+// look the same as an arbitrary ES API error in CR status.
+const conditionAdminCertUnavailable api.ClusterConditionType = "AdminCertUnavailable"
+
+// Reconcile drives one reconcile pass for clusterName in namespace: it opens
+// the root span via StartReconcileSpan and threads the resulting ctx into
+// every ES call it makes, so GetClusterHealth/SetShardAllocation/
+// UpdateReplicaCount all show up as children of ElasticsearchReconcile in
+// whatever backend InitTracing was pointed at. If the failure is an
+// AdminCertError, it's also recorded as a status condition on the owning
+// CR before Reconcile returns, so "waiting on cert rotation" is visible
+// without reading operator logs.
+//
+// This snapshot doesn't include the controller-runtime Reconciler that
+// would normally call this (no pkg/controller here to verify the real call
+// site against), so Reconcile is the narrowest honest stand-in: the
+// entrypoint the real Reconciler's Reconcile(ctx, req) would delegate to
+// once it has resolved req into a clusterName/namespace pair. It also
+// assumes api.Elasticsearch/api.ClusterCondition have the shape described
+// below -- pkg/apis/logging/v1 isn't present in this snapshot to confirm
+// against, so this is a best-effort match to the upstream CRD's
+// status.conditions convention.
+func Reconcile(ctx context.Context, clusterName, namespace string, desiredReplicas int32, k8sClient client.Client) (api.ClusterHealth, error) {
+	ctx, span := StartReconcileSpan(ctx, clusterName, namespace)
+	defer span.End()
+
+	health, err := GetClusterHealth(ctx, clusterName, namespace, k8sClient)
+	if err != nil {
+		return api.ClusterHealth{}, abortReconcile(ctx, clusterName, namespace, k8sClient, err,
+			fmt.Errorf("unable to get cluster health for %v/%v: %w", namespace, clusterName, err))
+	}
+
+	if _, err := UpdateReplicaCount(ctx, clusterName, namespace, k8sClient, desiredReplicas); err != nil {
+		return health, abortReconcile(ctx, clusterName, namespace, k8sClient, err,
+			fmt.Errorf("unable to reconcile replica count for %v/%v: %w", namespace, clusterName, err))
+	}
+
+	return health, nil
+}
+
+// abortReconcile records an AdminCertUnavailable status condition on
+// clusterName's CR when cause is (or wraps) an AdminCertError, then returns
+// reportErr unchanged so the caller's error path is otherwise untouched.
+func abortReconcile(ctx context.Context, clusterName, namespace string, k8sClient client.Client, cause, reportErr error) error {
+	var certErr *AdminCertError
+	if !errors.As(cause, &certErr) {
+		return reportErr
+	}
+
+	if condErr := setClusterCondition(ctx, clusterName, namespace, k8sClient, api.ClusterCondition{
+		Type:    conditionAdminCertUnavailable,
+		Status:  v1.ConditionTrue,
+		Reason:  "AdminCertUnavailable",
+		Message: certErr.Error(),
+	}); condErr != nil {
+		logrus.Errorf("reconcile: unable to set %v condition on %v/%v: %v", conditionAdminCertUnavailable, namespace, clusterName, condErr)
+	}
+
+	return reportErr
+}
+
+// setClusterCondition upserts condition into clusterName's
+// status.conditions by Type, and persists the CR status.
+func setClusterCondition(ctx context.Context, clusterName, namespace string, k8sClient client.Client, condition api.ClusterCondition) error {
+	cr := &api.Elasticsearch{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: clusterName, Namespace: namespace}, cr); err != nil {
+		return err
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+
+	updated := false
+	for i, existing := range cr.Status.Conditions {
+		if existing.Type == condition.Type {
+			cr.Status.Conditions[i] = condition
+			updated = true
+			break
+		}
+	}
+	if !updated {
+		cr.Status.Conditions = append(cr.Status.Conditions, condition)
+	}
+
+	return k8sClient.Status().Update(ctx, cr)
+}
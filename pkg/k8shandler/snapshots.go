@@ -0,0 +1,193 @@
+package k8shandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SnapshotRepositorySettings are the repository-type-specific settings
+// passed through verbatim to the ES `_snapshot/<name>` body, e.g. `bucket`/
+// `region` for `s3`, `bucket` for `gcs`, or `location` for `fs`.
+type SnapshotRepositorySettings map[string]interface{}
+
+// SLMPolicy mirrors the fields of an ES `_slm/policy/<name>` document.
+type SLMPolicy struct {
+	Schedule  string
+	Name      string
+	Indices   []string
+	Retention SLMRetention
+}
+
+// SLMRetention is the retention block of an SLM policy.
+type SLMRetention struct {
+	ExpireAfter string
+	MinCount    int32
+	MaxCount    int32
+}
+
+// RegisterRepository idempotently PUTs a `_snapshot/<name>` repository of
+// the given type (`s3`, `gcs`, `fs`, ...) with the supplied settings.
+func RegisterRepository(ctx context.Context, clusterName, namespace, repoName, repoType string, settings SnapshotRepositorySettings, k8sClient client.Client) (bool, error) {
+
+	payload := &esCurlStruct{
+		Method:      http.MethodPut,
+		URI:         fmt.Sprintf("_snapshot/%s", repoName),
+		RequestBody: fmt.Sprintf(`{"type":%q,"settings":%s}`, repoType, mapToJSON(settings)),
+		Ctx:         ctx,
+	}
+
+	curlESService(clusterName, namespace, payload, k8sClient)
+
+	acknowledged := false
+	if acknowledgedBool, ok := payload.ResponseBody["acknowledged"].(bool); ok {
+		acknowledged = acknowledgedBool
+	}
+
+	return (payload.StatusCode == 200 && acknowledged), payload.Error
+}
+
+// TakeSnapshot triggers a snapshot named snapshotName in repoName, waiting
+// for ES to accept the request (it does not block until the snapshot
+// completes -- poll ListSnapshots for that).
+func TakeSnapshot(ctx context.Context, clusterName, namespace, repoName, snapshotName string, indices []string, k8sClient client.Client) (bool, error) {
+
+	payload := &esCurlStruct{
+		Method:      http.MethodPut,
+		URI:         fmt.Sprintf("_snapshot/%s/%s", repoName, snapshotName),
+		RequestBody: fmt.Sprintf(`{"indices":%s}`, sliceToJSON(indices)),
+		Ctx:         ctx,
+	}
+
+	curlESService(clusterName, namespace, payload, k8sClient)
+
+	accepted := false
+	if acceptedBool, ok := payload.ResponseBody["accepted"].(bool); ok {
+		accepted = acceptedBool
+	}
+
+	return (payload.StatusCode == 200 && accepted), payload.Error
+}
+
+// RestoreSnapshot restores snapshotName from repoName. Callers must only
+// call this once the operator has confirmed spec.restore.approved is true
+// on the owning CR -- this function does not re-check that itself, since it
+// has no access to the CR, but it does quiesce the cluster first by
+// disabling shard allocation entirely and issuing a synchronized flush so
+// the restore doesn't race ongoing writes or have in-flight relocations
+// steal the freshly-restored shards. Once the cluster has been quiesced,
+// allocation is always re-enabled before returning -- on every exit path,
+// not just success -- since a cluster left with
+// cluster.routing.allocation.enable=none can never assign the restored (or
+// any other) shards and stays red indefinitely.
+func RestoreSnapshot(ctx context.Context, clusterName, namespace, repoName, snapshotName string, k8sClient client.Client) (bool, error) {
+
+	if _, err := restoreQuiesceFn(ctx, clusterName, namespace, "none", k8sClient); err != nil {
+		return false, fmt.Errorf("unable to quiesce cluster before restore: %v", err)
+	}
+
+	defer func() {
+		if _, err := restoreUnquiesceFn(ctx, clusterName, namespace, "all", k8sClient); err != nil {
+			logrus.Errorf("unable to re-enable shard allocation for %v/%v after restore: %v", namespace, clusterName, err)
+		}
+	}()
+
+	if _, err := restoreFlushFn(ctx, clusterName, namespace, k8sClient); err != nil {
+		return false, fmt.Errorf("unable to flush cluster before restore: %v", err)
+	}
+
+	return takeRestoreRequestFn(ctx, clusterName, namespace, repoName, snapshotName, k8sClient)
+}
+
+// restoreQuiesceFn, restoreUnquiesceFn, restoreFlushFn and
+// takeRestoreRequestFn are package vars so tests can assert RestoreSnapshot
+// calls them in order (quiesce, flush, restore, unquiesce) without standing
+// up a real Elasticsearch to exercise curlESService against.
+var (
+	restoreQuiesceFn     = SetShardAllocation
+	restoreUnquiesceFn   = SetShardAllocation
+	restoreFlushFn       = DoSynchronizedFlush
+	takeRestoreRequestFn = takeRestoreRequest
+)
+
+func takeRestoreRequest(ctx context.Context, clusterName, namespace, repoName, snapshotName string, k8sClient client.Client) (bool, error) {
+
+	payload := &esCurlStruct{
+		Method: http.MethodPost,
+		URI:    fmt.Sprintf("_snapshot/%s/%s/_restore", repoName, snapshotName),
+		Ctx:    ctx,
+	}
+
+	curlESService(clusterName, namespace, payload, k8sClient)
+
+	accepted := false
+	if acceptedBool, ok := payload.ResponseBody["accepted"].(bool); ok {
+		accepted = acceptedBool
+	}
+
+	return (payload.StatusCode == 200 && accepted), payload.Error
+}
+
+// PutSLMPolicy installs or updates a Snapshot Lifecycle Management policy
+// that snapshots policy.Indices into repoName on policy.Schedule, subject
+// to policy.Retention.
+func PutSLMPolicy(ctx context.Context, clusterName, namespace, repoName string, policy SLMPolicy, k8sClient client.Client) (bool, error) {
+
+	body := fmt.Sprintf(
+		`{"schedule":%q,"name":%q,"repository":%q,"config":{"indices":%s},"retention":{"expire_after":%q,"min_count":%d,"max_count":%d}}`,
+		policy.Schedule, policy.Name, repoName, sliceToJSON(policy.Indices),
+		policy.Retention.ExpireAfter, policy.Retention.MinCount, policy.Retention.MaxCount,
+	)
+
+	payload := &esCurlStruct{
+		Method:      http.MethodPut,
+		URI:         fmt.Sprintf("_slm/policy/%s", policy.Name),
+		RequestBody: body,
+		Ctx:         ctx,
+	}
+
+	curlESService(clusterName, namespace, payload, k8sClient)
+
+	acknowledged := false
+	if acknowledgedBool, ok := payload.ResponseBody["acknowledged"].(bool); ok {
+		acknowledged = acknowledgedBool
+	}
+
+	return (payload.StatusCode == 200 && acknowledged), payload.Error
+}
+
+// ListSnapshots returns the raw `_snapshot/<repo>/_all` response, keyed by
+// snapshot name, so callers can surface each snapshot's `state` in CR
+// status without this package needing its own typed snapshot model.
+func ListSnapshots(ctx context.Context, clusterName, namespace, repoName string, k8sClient client.Client) (map[string]interface{}, error) {
+
+	payload := &esCurlStruct{
+		Method: http.MethodGet,
+		URI:    fmt.Sprintf("_snapshot/%s/_all", repoName),
+		Ctx:    ctx,
+	}
+
+	curlESService(clusterName, namespace, payload, k8sClient)
+
+	return payload.ResponseBody, payload.Error
+}
+
+func mapToJSON(m map[string]interface{}) string {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+func sliceToJSON(s []string) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
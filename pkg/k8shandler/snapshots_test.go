@@ -0,0 +1,93 @@
+package k8shandler
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/openshift/elasticsearch-operator/pkg/apis/logging/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRestoreSnapshot_QuiescesFlushesThenRestoresInOrder(t *testing.T) {
+	var calls []string
+
+	origQuiesce, origUnquiesce, origFlush, origRestore := restoreQuiesceFn, restoreUnquiesceFn, restoreFlushFn, takeRestoreRequestFn
+	defer func() {
+		restoreQuiesceFn, restoreUnquiesceFn, restoreFlushFn, takeRestoreRequestFn = origQuiesce, origUnquiesce, origFlush, origRestore
+	}()
+
+	restoreQuiesceFn = func(ctx context.Context, clusterName, namespace string, state api.ShardAllocationState, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "quiesce:"+string(state))
+		return true, nil
+	}
+	restoreUnquiesceFn = func(ctx context.Context, clusterName, namespace string, state api.ShardAllocationState, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "unquiesce:"+string(state))
+		return true, nil
+	}
+	restoreFlushFn = func(ctx context.Context, clusterName, namespace string, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "flush")
+		return true, nil
+	}
+	takeRestoreRequestFn = func(ctx context.Context, clusterName, namespace, repoName, snapshotName string, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "restore:"+repoName+"/"+snapshotName)
+		return true, nil
+	}
+
+	ok, err := RestoreSnapshot(context.Background(), "es", "openshift-logging", "my-repo", "my-snapshot", nil)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot returned error: %v", err)
+	}
+	if !ok {
+		t.Fatal("RestoreSnapshot reported not accepted")
+	}
+
+	want := []string{"quiesce:none", "flush", "restore:my-repo/my-snapshot", "unquiesce:all"}
+	if len(calls) != len(want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("got calls %v, want %v", calls, want)
+		}
+	}
+}
+
+func TestRestoreSnapshot_AbortsBeforeFlushOrRestoreOnQuiesceError(t *testing.T) {
+	var calls []string
+
+	origQuiesce, origUnquiesce, origFlush, origRestore := restoreQuiesceFn, restoreUnquiesceFn, restoreFlushFn, takeRestoreRequestFn
+	defer func() {
+		restoreQuiesceFn, restoreUnquiesceFn, restoreFlushFn, takeRestoreRequestFn = origQuiesce, origUnquiesce, origFlush, origRestore
+	}()
+
+	restoreQuiesceFn = func(ctx context.Context, clusterName, namespace string, state api.ShardAllocationState, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "quiesce")
+		return false, errTestQuiesceFailed
+	}
+	restoreUnquiesceFn = func(ctx context.Context, clusterName, namespace string, state api.ShardAllocationState, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "unquiesce")
+		return true, nil
+	}
+	restoreFlushFn = func(ctx context.Context, clusterName, namespace string, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "flush")
+		return true, nil
+	}
+	takeRestoreRequestFn = func(ctx context.Context, clusterName, namespace, repoName, snapshotName string, k8sClient client.Client) (bool, error) {
+		calls = append(calls, "restore")
+		return true, nil
+	}
+
+	if _, err := RestoreSnapshot(context.Background(), "es", "openshift-logging", "my-repo", "my-snapshot", nil); err == nil {
+		t.Fatal("expected RestoreSnapshot to return an error when quiescing fails")
+	}
+
+	if len(calls) != 1 || calls[0] != "quiesce" {
+		t.Fatalf("got calls %v, want only [quiesce]", calls)
+	}
+}
+
+var errTestQuiesceFailed = &testError{"quiesce failed"}
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
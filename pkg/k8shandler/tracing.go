@@ -0,0 +1,75 @@
+package k8shandler
+
+import (
+	"context"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/openshift/elasticsearch-operator/pkg/k8shandler"
+
+func esTracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// InitTracing wires up a real span exporter when OTEL_EXPORTER_OTLP_ENDPOINT
+// is set, so esTracer's spans (and otelhttp's, via the transports
+// getClient and esclient.Client wrap their round trippers in) actually
+// leave the process instead of being dropped by the SDK's no-op default
+// TracerProvider. Call once from main/manager setup, before any reconcile
+// runs. If the env var is unset this is a no-op and every span is simply
+// discarded, same as before this existed.
+//
+// The returned shutdown func flushes and closes the exporter; callers should
+// defer it.
+func InitTracing(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		logrus.Debug("OTEL_EXPORTER_OTLP_ENDPOINT not set, reconcile spans will not be exported")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String("elasticsearch-operator"),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// StartReconcileSpan starts the root span for a single Elasticsearch CR
+// reconcile. The returned context should be threaded down into every
+// curlESService call (via esCurlStruct.Ctx) so that cluster health checks,
+// shard allocation flips and flush/sync calls all show up as children of
+// this span -- the quickest way to see why a rolling restart stalled in
+// DoSynchronizedFlush or why a replica update hung.
+func StartReconcileSpan(ctx context.Context, clusterName, namespace string) (context.Context, trace.Span) {
+	ctx, span := esTracer().Start(ctx, "ElasticsearchReconcile")
+	span.SetAttributes(
+		attribute.String("es.cluster", clusterName),
+		attribute.String("es.namespace", namespace),
+	)
+	return ctx, span
+}